@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+type ctxKey int
+
+const userCtxKey ctxKey = 0
+
+func withUser(r *http.Request, u *User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userCtxKey, u))
+}
+
+func userFromContext(r *http.Request) *User {
+	u, _ := r.Context().Value(userCtxKey).(*User)
+	return u
+}
+
+// pathLocator extracts the root+relative-path a request targets, reading
+// from the query string (GET handlers) or the parsed form (POST
+// handlers) depending on the route.
+type pathLocator func(r *http.Request) (root, path string)
+
+func queryLocator(r *http.Request) (string, string) {
+	q := r.URL.Query()
+	return q.Get("root"), q.Get("path")
+}
+
+func formLocator(r *http.Request) (string, string) {
+	r.ParseForm()
+	return r.Form.Get("root"), r.Form.Get("path")
+}
+
+// requirePermission wraps a handler so it 403s unless the authenticated
+// user (attached to context by basicAuth) is allowed verb on the root and
+// path the request targets.
+func requirePermission(verb string, locate pathLocator) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			user := userFromContext(r)
+			if user == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			root, path := locate(r)
+			if root == "" && len(cfg.AllowedRoots) > 0 {
+				root = cfg.AllowedRoots[0]
+			}
+			if !user.Can(verb, root, path) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// canDo is exposed to pageHTML as the "can" template func, so buttons for
+// actions the current user isn't allowed are hidden rather than just
+// rejected server-side.
+func canDo(u *User, verb, root, path string) bool {
+	if u == nil {
+		return false
+	}
+	return u.Can(verb, root, path)
+}
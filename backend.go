@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// Backend abstracts the storage operations the file manager needs so the
+// same handlers can browse a local disk, an S3 bucket, or an SFTP server.
+// Every method takes paths relative to the backend's own root (already
+// validated by resolveSafePath), using "/" as the separator regardless of
+// the underlying storage.
+type Backend interface {
+	Stat(path string) (fs.FileInfo, error)
+	ReadDir(path string) ([]fs.FileInfo, error)
+	Open(path string) (io.ReadCloser, error)
+	Create(path string) (io.WriteCloser, error)
+	Mkdir(path string) error
+	Remove(path string) error
+	Rename(oldPath, newPath string) error
+	Walk(path string, fn filepath.WalkFunc) error
+}
+
+// backendFor returns the Backend registered for an allowed root, or nil if
+// the root is unknown. Callers should treat a nil Backend as a 400, the
+// same way resolveSafePath already rejects an unknown root.
+func backendFor(root string) Backend {
+	return cfg.Backends[root]
+}
+
+// parseRootBackend splits one ALLOWED_ROOTS entry into its display label
+// and a Backend, dispatching on URI scheme: "s3://bucket/prefix",
+// "sftp://user@host/path", or a plain filesystem path for localfs.
+func parseRootBackend(entry string) (label string, backend Backend, err error) {
+	switch {
+	case hasScheme(entry, "s3"):
+		return newS3BackendFromURI(entry)
+	case hasScheme(entry, "sftp"):
+		return newSFTPBackendFromURI(entry)
+	default:
+		label = filepath.Clean(entry)
+		return label, newLocalFSBackend(label), nil
+	}
+}
+
+func hasScheme(entry, scheme string) bool {
+	prefix := scheme + "://"
+	return len(entry) >= len(prefix) && entry[:len(prefix)] == prefix
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localFSBackend is the default Backend, implemented directly on top of
+// os/filepath. It preserves the behavior the file manager had before
+// backends existed: resolveSafePath still does all the escape checking,
+// this type just joins the validated relative path onto its root and
+// performs the actual syscalls.
+type localFSBackend struct {
+	root string
+}
+
+func newLocalFSBackend(root string) *localFSBackend {
+	return &localFSBackend{root: root}
+}
+
+func (b *localFSBackend) abs(p string) string {
+	return filepath.Join(b.root, p)
+}
+
+func (b *localFSBackend) Stat(p string) (fs.FileInfo, error) {
+	return os.Stat(b.abs(p))
+}
+
+func (b *localFSBackend) ReadDir(p string) ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(b.abs(p))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (b *localFSBackend) Open(p string) (io.ReadCloser, error) {
+	return os.Open(b.abs(p))
+}
+
+func (b *localFSBackend) Create(p string) (io.WriteCloser, error) {
+	return os.Create(b.abs(p))
+}
+
+func (b *localFSBackend) Mkdir(p string) error {
+	return os.MkdirAll(b.abs(p), 0o755)
+}
+
+func (b *localFSBackend) Remove(p string) error {
+	abs := b.abs(p)
+	st, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+	if st.IsDir() {
+		return os.RemoveAll(abs)
+	}
+	return os.Remove(abs)
+}
+
+func (b *localFSBackend) Rename(oldPath, newPath string) error {
+	return os.Rename(b.abs(oldPath), b.abs(newPath))
+}
+
+// ResolveSymlink reads the link at p and reports whether it points outside
+// the backend's root, so archive.go can refuse to follow it off-root.
+func (b *localFSBackend) ResolveSymlink(p string) (target string, escapesRoot bool, err error) {
+	abs := b.abs(p)
+	target, err = os.Readlink(abs)
+	if err != nil {
+		return "", false, err
+	}
+	resolved := target
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(abs), resolved)
+	}
+	rel, relErr := filepath.Rel(b.root, resolved)
+	if relErr != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return target, true, nil
+	}
+	return target, false, nil
+}
+
+func (b *localFSBackend) Walk(p string, fn filepath.WalkFunc) error {
+	base := b.abs(p)
+	return filepath.Walk(base, func(walked string, info fs.FileInfo, err error) error {
+		rel, relErr := filepath.Rel(b.root, walked)
+		if relErr != nil {
+			rel = walked
+		}
+		return fn(rel, info, err)
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLocalFSBackendResolveSymlinkDetectsEscape(t *testing.T) {
+	dir := t.TempDir()
+	b := newLocalFSBackend(dir)
+
+	if err := os.WriteFile(dir+"/inside.txt", []byte("hi"), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := os.Symlink(dir+"/inside.txt", dir+"/ok-link"); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := os.Symlink("/etc/passwd", dir+"/escape-link"); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if _, escapes, err := b.ResolveSymlink("ok-link"); err != nil || escapes {
+		t.Errorf("in-root symlink: escapes=%v err=%v, want escapes=false", escapes, err)
+	}
+	if _, escapes, err := b.ResolveSymlink("escape-link"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !escapes {
+		t.Error("symlink to /etc/passwd should be reported as escaping the root")
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Backend implements Backend on top of an S3 (or S3-compatible) bucket.
+// Relative paths are joined onto prefix with "/" to form the object key;
+// "directories" are synthesized from common key prefixes since S3 has no
+// native concept of one.
+type s3Backend struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+func newS3BackendFromURI(uri string) (string, Backend, error) {
+	rest := strings.TrimPrefix(uri, "s3://")
+	parts := strings.SplitN(rest, "/", 2)
+	bucket := parts[0]
+	prefix := ""
+	if len(parts) == 2 {
+		prefix = strings.Trim(parts[1], "/")
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return "", nil, fmt.Errorf("s3 backend %s: %w", uri, err)
+	}
+	client := s3.NewFromConfig(awsCfg)
+	b := &s3Backend{
+		client:   client,
+		uploader: manager.NewUploader(client),
+		bucket:   bucket,
+		prefix:   prefix,
+	}
+	return uri, b, nil
+}
+
+func (b *s3Backend) key(p string) string {
+	return path.Join(b.prefix, filepath.ToSlash(p))
+}
+
+type s3FileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (i s3FileInfo) Name() string       { return i.name }
+func (i s3FileInfo) Size() int64        { return i.size }
+func (i s3FileInfo) Mode() fs.FileMode  { return 0o644 }
+func (i s3FileInfo) ModTime() time.Time { return i.modTime }
+func (i s3FileInfo) IsDir() bool        { return i.isDir }
+func (i s3FileInfo) Sys() any           { return nil }
+
+func (b *s3Backend) Stat(p string) (fs.FileInfo, error) {
+	ctx := context.Background()
+	key := b.key(p)
+	out, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(b.bucket), Key: aws.String(key)})
+	if err == nil {
+		return s3FileInfo{name: path.Base(key), size: aws.ToInt64(out.ContentLength), modTime: aws.ToTime(out.LastModified)}, nil
+	}
+	// Not an object; treat as a "directory" if any object has this prefix.
+	listOut, listErr := b.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket), Prefix: aws.String(key + "/"), MaxKeys: aws.Int32(1),
+	})
+	if listErr == nil && len(listOut.Contents) > 0 {
+		return s3FileInfo{name: path.Base(key), isDir: true}, nil
+	}
+	return nil, err
+}
+
+func (b *s3Backend) ReadDir(p string) ([]fs.FileInfo, error) {
+	ctx := context.Background()
+	prefix := b.key(p)
+	if prefix != "" {
+		prefix += "/"
+	}
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket), Prefix: aws.String(prefix), Delimiter: aws.String("/"),
+	})
+	infos := []fs.FileInfo{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range page.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(cp.Prefix), prefix), "/")
+			infos = append(infos, s3FileInfo{name: name, isDir: true})
+		}
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if name == "" {
+				continue
+			}
+			infos = append(infos, s3FileInfo{name: name, size: aws.ToInt64(obj.Size), modTime: aws.ToTime(obj.LastModified)})
+		}
+	}
+	return infos, nil
+}
+
+// Open returns a seekable reader so serveBackendFile can honor Range and
+// conditional requests against S3 the same way it does for local files,
+// rather than always streaming the whole object from byte zero.
+func (b *s3Backend) Open(p string) (io.ReadCloser, error) {
+	ctx := context.Background()
+	key := b.key(p)
+	head, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(b.bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, err
+	}
+	return newS3SeekableReader(ctx, b.client, b.bucket, key, aws.ToInt64(head.ContentLength)), nil
+}
+
+// s3SeekableReader implements io.ReadSeeker over S3 by lazily issuing a
+// ranged GetObject starting at the current offset on the first Read after
+// a Seek, instead of buffering the object or re-fetching on every Seek
+// call (http.ServeContent seeks to the end just to learn the size).
+type s3SeekableReader struct {
+	ctx    context.Context
+	client *s3.Client
+	bucket string
+	key    string
+	size   int64
+
+	body   io.ReadCloser
+	offset int64
+}
+
+func newS3SeekableReader(ctx context.Context, client *s3.Client, bucket, key string, size int64) *s3SeekableReader {
+	return &s3SeekableReader{ctx: ctx, client: client, bucket: bucket, key: key, size: size}
+}
+
+func (r *s3SeekableReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		if err := r.openAt(r.offset); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *s3SeekableReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.offset + offset
+	case io.SeekEnd:
+		target = r.size + offset
+	default:
+		return 0, fmt.Errorf("s3: invalid whence %d", whence)
+	}
+	if target < 0 {
+		return 0, fmt.Errorf("s3: negative seek position")
+	}
+	if target != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = target
+	return r.offset, nil
+}
+
+func (r *s3SeekableReader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
+
+func (r *s3SeekableReader) openAt(offset int64) error {
+	in := &s3.GetObjectInput{Bucket: aws.String(r.bucket), Key: aws.String(r.key)}
+	if offset > 0 {
+		in.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	out, err := r.client.GetObject(r.ctx, in)
+	if err != nil {
+		return err
+	}
+	r.body = out.Body
+	return nil
+}
+
+// s3WriteCloser streams writes into a pipe consumed by the multipart
+// uploader in the background, so callers can io.Copy into it directly.
+type s3WriteCloser struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *s3WriteCloser) Write(p []byte) (int, error) { return w.pw.Write(p) }
+func (w *s3WriteCloser) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+func (b *s3Backend) Create(p string) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.uploader.Upload(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String(b.bucket), Key: aws.String(b.key(p)), Body: pr,
+		})
+		pr.CloseWithError(err)
+		done <- err
+	}()
+	return &s3WriteCloser{pw: pw, done: done}, nil
+}
+
+func (b *s3Backend) Mkdir(p string) error {
+	// S3 has no real directories; a zero-byte marker object is enough for
+	// the listing logic above to show it until a real object lands in it.
+	_, err := b.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket), Key: aws.String(b.key(p) + "/"),
+	})
+	return err
+}
+
+func (b *s3Backend) Remove(p string) error {
+	ctx := context.Background()
+	key := b.key(p)
+	if st, err := b.Stat(p); err == nil && st.IsDir() {
+		paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(b.bucket), Prefix: aws.String(key + "/"),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			var ids []types.ObjectIdentifier
+			for _, obj := range page.Contents {
+				ids = append(ids, types.ObjectIdentifier{Key: obj.Key})
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			if _, err := b.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(b.bucket), Delete: &types.Delete{Objects: ids},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(b.bucket), Key: aws.String(key)})
+	return err
+}
+
+func (b *s3Backend) Rename(oldPath, newPath string) error {
+	ctx := context.Background()
+	src := fmt.Sprintf("%s/%s", b.bucket, b.key(oldPath))
+	if _, err := b.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket: aws.String(b.bucket), Key: aws.String(b.key(newPath)), CopySource: aws.String(src),
+	}); err != nil {
+		return err
+	}
+	return b.Remove(oldPath)
+}
+
+func (b *s3Backend) Walk(p string, fn filepath.WalkFunc) error {
+	ctx := context.Background()
+	prefix := b.key(p)
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket), Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fn(p, nil, err)
+		}
+		for _, obj := range page.Contents {
+			rel := strings.TrimPrefix(strings.TrimPrefix(aws.ToString(obj.Key), b.prefix), "/")
+			info := s3FileInfo{name: path.Base(rel), size: aws.ToInt64(obj.Size), modTime: aws.ToTime(obj.LastModified)}
+			if err := fn(rel, info, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+//go:embed static
+var staticFS embed.FS
+
+// staticHandler serves the embedded CSS/JS under /static/ so htmx and the
+// app's own styles can be self-hosted instead of pulled from a CDN on
+// every page load. Static assets are content-addressed by path only (no
+// per-file versioning yet), so they're cached for a day rather than
+// forever.
+func staticHandler() http.Handler {
+	sub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.FileServer(http.FS(sub))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=86400")
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// computeETag builds a strong ETag from size and mtime: cheap to
+// compute, stable across restarts, and changes whenever the file's
+// content could have.
+func computeETag(size int64, modTime time.Time) string {
+	return fmt.Sprintf(`"%x-%x"`, size, modTime.UnixNano())
+}
+
+// serveBackendFile streams a single file out of a Backend, honoring
+// conditional requests (If-None-Match / If-Modified-Since) and byte
+// ranges (including multi-range requests) whenever the backend can hand
+// back a seekable reader. A reader that can only stream sequentially
+// falls back to a plain copy without range support.
+func serveBackendFile(w http.ResponseWriter, r *http.Request, backend Backend, relPath string, info fs.FileInfo, cacheControl string) {
+	f, err := backend.Open(relPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	if ct := mime.TypeByExtension(filepath.Ext(relPath)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	etag := computeETag(info.Size(), info.ModTime())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, filepath.Base(relPath), info.ModTime(), rs)
+		return
+	}
+
+	// No seek support: If-None-Match is still checked against the ETag,
+	// but Range requests can't be honored without buffering the whole
+	// file, so we just stream it once from the start.
+	if inm := r.Header.Get("If-None-Match"); inm == etag || inm == "*" {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Accept-Ranges", "none")
+	if info.Size() > 0 {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
+	}
+	io.Copy(w, f)
+}
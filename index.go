@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// IndexFileItem is a single indexed filesystem entry, keyed by its path
+// relative to the root it was discovered under.
+type IndexFileItem struct {
+	Path string
+	Info os.FileInfo
+}
+
+// rootIndex holds the flattened listing for one allowed root.
+type rootIndex struct {
+	mu    sync.RWMutex
+	items []IndexFileItem
+}
+
+// Indexer walks every allowed root on startup and on a fixed interval,
+// keeping an in-memory snapshot that /search queries against.
+type Indexer struct {
+	interval time.Duration
+	roots    map[string]*rootIndex
+}
+
+func newIndexer(roots []string, interval time.Duration) *Indexer {
+	idx := &Indexer{interval: interval, roots: map[string]*rootIndex{}}
+	for _, r := range roots {
+		idx.roots[r] = &rootIndex{}
+	}
+	return idx
+}
+
+// Start performs an initial full walk and then re-walks every interval.
+// It also watches local filesystem roots for changes and re-indexes only
+// the affected subtree, debounced so a burst of events only triggers one
+// walk. S3/SFTP roots have no filesystem to watch, so they only ever pick
+// up changes on the next interval re-walk.
+func (idx *Indexer) Start() {
+	for root := range idx.roots {
+		idx.reindexRoot(root)
+	}
+	events := make(chan watchEvent, 256)
+	for root := range idx.roots {
+		if strings.Contains(root, "://") {
+			continue
+		}
+		go idx.watchRoot(root, events)
+	}
+	go idx.debounceLoop(events)
+	go func() {
+		ticker := time.NewTicker(idx.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for root := range idx.roots {
+				idx.reindexRoot(root)
+			}
+		}
+	}()
+}
+
+func (idx *Indexer) reindexRoot(root string) {
+	backend := backendFor(root)
+	if backend == nil {
+		return
+	}
+	items := make([]IndexFileItem, 0, 1024)
+	backend.Walk(".", func(rel string, info os.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return nil
+		}
+		items = append(items, IndexFileItem{Path: rel, Info: info})
+		return nil
+	})
+	ri := idx.roots[root]
+	ri.mu.Lock()
+	ri.items = items
+	ri.mu.Unlock()
+}
+
+// reindexSubtree re-walks only the given relative path inside root,
+// replacing the entries under it. Used by the debounced watcher so a
+// change deep in a large disk doesn't force a full re-walk.
+func (idx *Indexer) reindexSubtree(root, relDir string) {
+	ri := idx.roots[root]
+	if ri == nil {
+		return
+	}
+	abs := filepath.Join(root, relDir)
+	fresh := make([]IndexFileItem, 0, 64)
+	filepath.Walk(abs, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil {
+			return nil
+		}
+		fresh = append(fresh, IndexFileItem{Path: rel, Info: info})
+		return nil
+	})
+
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	kept := ri.items[:0:0]
+	prefix := relDir + string(os.PathSeparator)
+	if relDir == "." {
+		prefix = "" // the whole root was re-walked, so nothing old is kept
+	}
+	for _, it := range ri.items {
+		if it.Path != relDir && !strings.HasPrefix(it.Path, prefix) {
+			kept = append(kept, it)
+		}
+	}
+	ri.items = append(kept, fresh...)
+}
+
+// watchEvent names one root+subdirectory that changed.
+type watchEvent struct {
+	root   string
+	relDir string
+}
+
+// watchRoot watches root for filesystem changes and emits a watchEvent per
+// affected directory. fsnotify watches aren't recursive, so every
+// directory under root is watched individually, and newly created
+// directories are added as they appear.
+func (idx *Indexer) watchRoot(root string, events chan<- watchEvent) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("indexer: fsnotify unavailable for %s, falling back to interval re-walk: %v", root, err)
+		return
+	}
+	defer w.Close()
+
+	filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			w.Add(p)
+		}
+		return nil
+	})
+
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if info, statErr := os.Stat(ev.Name); statErr == nil && info.IsDir() {
+					w.Add(ev.Name)
+				}
+			}
+			relDir, relErr := filepath.Rel(root, filepath.Dir(ev.Name))
+			if relErr != nil {
+				continue
+			}
+			events <- watchEvent{root: root, relDir: relDir}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Println("indexer: fsnotify error:", err)
+		}
+	}
+}
+
+// debounceLoop coalesces a burst of watchEvents for the same root+relDir
+// into a single reindexSubtree call, so a keystroke-speed burst of writes
+// doesn't cause a full re-walk per event.
+func (idx *Indexer) debounceLoop(events <-chan watchEvent) {
+	const debounce = 2 * time.Second
+	pending := map[watchEvent]struct{}{}
+	var mu sync.Mutex
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	go func() {
+		for ev := range events {
+			mu.Lock()
+			pending[ev] = struct{}{}
+			mu.Unlock()
+			timer.Reset(debounce)
+		}
+	}()
+
+	for range timer.C {
+		mu.Lock()
+		toFlush := pending
+		pending = map[watchEvent]struct{}{}
+		mu.Unlock()
+		for ev := range toFlush {
+			idx.reindexSubtree(ev.root, ev.relDir)
+		}
+	}
+}
+
+type searchResult struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Root    string `json:"root"`
+	IsDir   bool   `json:"is_dir"`
+	Size    int64  `json:"size"`
+	ModTime string `json:"mod_time"`
+	Kind    string `json:"kind"`
+}
+
+func entryKind(isDir bool, name string) string {
+	if isDir {
+		return "dir"
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp":
+		return "image"
+	case ".mp4", ".mkv", ".mov", ".avi", ".webm":
+		return "video"
+	case ".zip", ".tar", ".gz", ".tgz", ".7z", ".rar":
+		return "archive"
+	default:
+		return "file"
+	}
+}
+
+func parseSizeBound(s string) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func parseTimeBound(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// handleSearch answers /search?root=&path=&q=&kind=&min_size=&max_size=&mtime_from=&mtime_to=
+// scoped to the subtree rooted at root+path, honoring the same
+// resolveSafePath rules as browsing.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	root := q.Get("root")
+	if root == "" && len(cfg.AllowedRoots) > 0 {
+		root = cfg.AllowedRoots[0]
+	}
+	scopeRel := q.Get("path")
+	root, _, scopeSafe, err := resolveSafePath(root, scopeRel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ri := globalIndexer.roots[root]
+	if ri == nil {
+		http.Error(w, "root not indexed", http.StatusBadRequest)
+		return
+	}
+
+	term := strings.ToLower(q.Get("q"))
+	kind := q.Get("kind")
+	minSize, hasMin := parseSizeBound(q.Get("min_size"))
+	maxSize, hasMax := parseSizeBound(q.Get("max_size"))
+	mtimeFrom, hasFrom := parseTimeBound(q.Get("mtime_from"))
+	mtimeTo, hasTo := parseTimeBound(q.Get("mtime_to"))
+
+	var scopePrefix string
+	if scopeSafe != "." {
+		scopePrefix = scopeSafe + string(os.PathSeparator)
+	}
+
+	user := userFromContext(r)
+
+	ri.mu.RLock()
+	results := make([]searchResult, 0, 32)
+	for _, it := range ri.items {
+		if scopePrefix != "" && it.Path != scopeSafe && !strings.HasPrefix(it.Path, scopePrefix) {
+			continue
+		}
+		// The search scope only proves the requested root+path is
+		// listable; the index walks the whole subtree, so each match
+		// still needs its own check in case a narrower glob denies it.
+		if user == nil || !user.Can(verbList, root, it.Path) {
+			continue
+		}
+		name := filepath.Base(it.Path)
+		if term != "" {
+			matched := strings.Contains(strings.ToLower(name), term)
+			if !matched {
+				if ok, _ := filepath.Match(term, strings.ToLower(name)); ok {
+					matched = true
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		k := entryKind(it.Info.IsDir(), name)
+		if kind != "" && kind != k {
+			continue
+		}
+		if hasMin && it.Info.Size() < minSize {
+			continue
+		}
+		if hasMax && it.Info.Size() > maxSize {
+			continue
+		}
+		if hasFrom && it.Info.ModTime().Before(mtimeFrom) {
+			continue
+		}
+		if hasTo && it.Info.ModTime().After(mtimeTo) {
+			continue
+		}
+		results = append(results, searchResult{
+			Name:    name,
+			Path:    it.Path,
+			Root:    root,
+			IsDir:   it.Info.IsDir(),
+			Size:    it.Info.Size(),
+			ModTime: it.Info.ModTime().Format("2006-01-02 15:04"),
+			Kind:    k,
+		})
+	}
+	ri.mu.RUnlock()
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+		return
+	}
+
+	entries := make([]FileEntry, 0, len(results))
+	for _, res := range results {
+		modTime, _ := time.Parse("2006-01-02 15:04", res.ModTime)
+		entries = append(entries, FileEntry{
+			Name:        res.Name,
+			Path:        filepath.Join(root, res.Path),
+			RelPath:     res.Path,
+			IsDir:       res.IsDir,
+			Size:        res.Size,
+			ModTime:     modTime,
+			PreviewKind: previewKind(res.Name),
+		})
+	}
+	data := PageData{
+		Title:       "Go File Manager",
+		CurrentRoot: root,
+		CurrentPath: scopeSafe,
+		Breadcrumb:  buildBreadcrumb(root, scopeSafe),
+		Entries:     entries,
+		Roots:       cfg.AllowedRoots,
+		User:        userFromContext(r),
+		Flash:       fmt.Sprintf("%d resultado(s) para %q", len(results), q.Get("q")),
+	}
+	render(w, data)
+}
+
+var globalIndexer *Indexer
+
+func indexIntervalFromEnv() time.Duration {
+	mins, err := strconv.Atoi(getenv("INDEX_INTERVAL_MINUTES", "15"))
+	if err != nil || mins <= 0 {
+		mins = 15
+	}
+	return time.Duration(mins) * time.Minute
+}
@@ -1,13 +1,10 @@
 package main
 
 import (
-	"archive/zip"
-	"bytes"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"io/fs"
 	"log"
 	"mime/multipart"
 	"net/http"
@@ -20,8 +17,7 @@ import (
 
 type Config struct {
 	AllowedRoots []string
-	Username     string
-	Password     string
+	Backends     map[string]Backend
 }
 
 func getenv(key, def string) string {
@@ -43,28 +39,36 @@ func readSecretOrEnv(fileVar, envVar, def string) string {
 func loadConfig() Config {
 	rootsEnv := getenv("ALLOWED_ROOTS", "/data/sdd1,/data/hdd1")
 	roots := []string{}
-	for _, p := range strings.Split(rootsEnv, ",") {
-		p = filepath.Clean(strings.TrimSpace(p))
-		if p != "" {
-			roots = append(roots, p)
+	backends := map[string]Backend{}
+	for _, entry := range strings.Split(rootsEnv, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		label, backend, err := parseRootBackend(entry)
+		if err != nil {
+			log.Fatalf("ALLOWED_ROOTS entry %q: %v", entry, err)
 		}
+		roots = append(roots, label)
+		backends[label] = backend
 	}
 	return Config{
 		AllowedRoots: roots,
-		Username:     readSecretOrEnv("USERNAME_FILE", "USERNAME", "admin"),
-		Password:     readSecretOrEnv("PASSWORD_FILE", "PASSWORD", "changeme"),
+		Backends:     backends,
 	}
 }
 
 var cfg Config
+var userStore *UserStore
 
 type FileEntry struct {
-	Name    string
-	Path    string
-	RelPath string
-	IsDir   bool
-	Size    int64
-	ModTime time.Time
+	Name        string
+	Path        string
+	RelPath     string
+	IsDir       bool
+	Size        int64
+	ModTime     time.Time
+	PreviewKind string
 }
 
 type PageData struct {
@@ -75,6 +79,7 @@ type PageData struct {
 	Entries     []FileEntry
 	Roots       []string
 	Flash       string
+	User        *User
 }
 
 type Crumb struct {
@@ -90,7 +95,9 @@ func must[T any](v T, err error) T {
 }
 
 func resolveSafePath(rootParam, rel string) (string, string, string, error) {
-	rootParam = filepath.Clean(rootParam)
+	if !strings.Contains(rootParam, "://") {
+		rootParam = filepath.Clean(rootParam)
+	}
 	var root string
 	for _, r := range cfg.AllowedRoots {
 		if r == rootParam {
@@ -138,26 +145,22 @@ func buildBreadcrumb(root, rel string) []Crumb {
 	return crumbs
 }
 
-func listDir(abs, root string) ([]FileEntry, error) {
-	entries, err := os.ReadDir(abs)
+func listDir(backend Backend, relPath string) ([]FileEntry, error) {
+	infos, err := backend.ReadDir(relPath)
 	if err != nil {
 		return nil, err
 	}
-	items := make([]FileEntry, 0, len(entries))
-	for _, e := range entries {
-		info, err := e.Info()
-		if err != nil {
-			continue
-		}
-		itemPath := filepath.Join(abs, e.Name())
-		rel, _ := filepath.Rel(root, itemPath)
+	items := make([]FileEntry, 0, len(infos))
+	for _, info := range infos {
+		itemRel := filepath.Join(relPath, info.Name())
 		items = append(items, FileEntry{
-			Name:    e.Name(),
-			Path:    itemPath,
-			RelPath: rel,
-			IsDir:   e.IsDir(),
-			Size:    info.Size(),
-			ModTime: info.ModTime(),
+			Name:        info.Name(),
+			Path:        itemRel,
+			RelPath:     itemRel,
+			IsDir:       info.IsDir(),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			PreviewKind: previewKind(info.Name()),
 		})
 	}
 	sort.Slice(items, func(i, j int) bool {
@@ -199,15 +202,31 @@ func withSecurityHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// basicAuth authenticates the request via the login-cookie session if
+// present, falling back to HTTP Basic auth against the user store. On
+// success it attaches the *User to the request context for downstream
+// permission checks.
 func basicAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if username := sessionUsername(r); username != "" {
+			if user, ok := userStore.Users[username]; ok {
+				next(w, withUser(r, user))
+				return
+			}
+		}
 		u, p, ok := r.BasicAuth()
-		if !ok || u != cfg.Username || p != cfg.Password {
+		if !ok {
 			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		next(w, r)
+		user, ok := userStore.Authenticate(u, p)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, withUser(r, user))
 	}
 }
 
@@ -215,6 +234,7 @@ func render(w http.ResponseWriter, data PageData) {
 	tmpl := template.Must(template.New("page").Funcs(template.FuncMap{
 		"humanSize": humanSize,
 		"fmtTime":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+		"can":       canDo,
 	}).Parse(pageHTML))
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := tmpl.Execute(w, data); err != nil {
@@ -229,16 +249,17 @@ func handleBrowse(w http.ResponseWriter, r *http.Request) {
 	}
 	rel := r.URL.Query().Get("path")
 
-	root, abs, relSafe, err := resolveSafePath(root, rel)
+	root, _, relSafe, err := resolveSafePath(root, rel)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if st, err := os.Stat(abs); err == nil && !st.IsDir() {
-		http.ServeFile(w, r, abs)
+	backend := backendFor(root)
+	if st, err := backend.Stat(relSafe); err == nil && !st.IsDir() {
+		serveBackendFile(w, r, backend, relSafe, st, "no-cache")
 		return
 	}
-	items, err := listDir(abs, root)
+	items, err := listDir(backend, relSafe)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -250,70 +271,11 @@ func handleBrowse(w http.ResponseWriter, r *http.Request) {
 		Breadcrumb:  buildBreadcrumb(root, relSafe),
 		Entries:     items,
 		Roots:       cfg.AllowedRoots,
+		User:        userFromContext(r),
 	}
 	render(w, data)
 }
 
-func addFileToZip(zw *zip.Writer, path, name string) error {
-	f, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	w, err := zw.Create(name)
-	if err != nil {
-		return err
-	}
-	_, err = io.Copy(w, f)
-	return err
-}
-
-func handleZip(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, err.Error(), 400)
-		return
-	}
-	root := r.Form.Get("root")
-	rel := r.Form.Get("path")
-	_, abs, relSafe, err := resolveSafePath(root, rel)
-	if err != nil {
-		http.Error(w, err.Error(), 400)
-		return
-	}
-	st, err := os.Stat(abs)
-	if err != nil {
-		http.Error(w, err.Error(), 404)
-		return
-	}
-	var buf bytes.Buffer
-	zw := zip.NewWriter(&buf)
-	if st.IsDir() {
-		filepath.WalkDir(abs, func(p string, d fs.DirEntry, e error) error {
-			if e != nil {
-				return e
-			}
-			if d.IsDir() {
-				return nil
-			}
-			relp, _ := filepath.Rel(abs, p)
-			return addFileToZip(zw, p, relp)
-		})
-	} else {
-		addFileToZip(zw, abs, filepath.Base(abs))
-	}
-	zw.Close()
-	name := strings.ReplaceAll(relSafe, string(os.PathSeparator), "_")
-	if name == "" {
-		name = filepath.Base(abs)
-	}
-	if name == "" {
-		name = "download"
-	}
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", name))
-	w.Write(buf.Bytes())
-}
-
 func handleUpload(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseMultipartForm(512 << 20); err != nil { // 512MB
 		http.Error(w, err.Error(), 400)
@@ -321,18 +283,19 @@ func handleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 	root := r.FormValue("root")
 	rel := r.FormValue("path")
-	_, abs, relSafe, err := resolveSafePath(root, rel)
+	root, _, relSafe, err := resolveSafePath(root, rel)
 	if err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
+	backend := backendFor(root)
 	files := r.MultipartForm.File["files[]"]
 	if len(files) == 0 {
 		http.Error(w, "no files", 400)
 		return
 	}
 	for _, fh := range files {
-		if err := saveUploadedFile(abs, fh); err != nil {
+		if err := saveUploadedFile(backend, relSafe, fh); err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -340,14 +303,14 @@ func handleUpload(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("/?root=%s&path=%s", urlq(root), urlq(relSafe)), http.StatusSeeOther)
 }
 
-func saveUploadedFile(dir string, fh *multipart.FileHeader) error {
+func saveUploadedFile(backend Backend, relDir string, fh *multipart.FileHeader) error {
 	src, err := fh.Open()
 	if err != nil {
 		return err
 	}
 	defer src.Close()
-	dstPath := filepath.Join(dir, filepath.Base(fh.Filename))
-	dst, err := os.Create(dstPath)
+	dstPath := filepath.Join(relDir, filepath.Base(fh.Filename))
+	dst, err := backend.Create(dstPath)
 	if err != nil {
 		return err
 	}
@@ -368,13 +331,13 @@ func handleMkdir(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "name required", 400)
 		return
 	}
-	_, abs, relSafe, err := resolveSafePath(root, rel)
+	root, _, relSafe, err := resolveSafePath(root, rel)
 	if err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	target := filepath.Join(abs, filepath.Base(name))
-	if err := os.MkdirAll(target, 0o755); err != nil {
+	target := filepath.Join(relSafe, filepath.Base(name))
+	if err := backendFor(root).Mkdir(target); err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
@@ -393,23 +356,13 @@ func handleDelete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "name required", 400)
 		return
 	}
-	_, abs, relSafe, err := resolveSafePath(root, rel)
+	root, _, relSafe, err := resolveSafePath(root, rel)
 	if err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	target := filepath.Join(abs, filepath.Base(name))
-	st, err := os.Stat(target)
-	if err != nil {
-		http.Error(w, err.Error(), 404)
-		return
-	}
-	if st.IsDir() {
-		err = os.RemoveAll(target)
-	} else {
-		err = os.Remove(target)
-	}
-	if err != nil {
+	target := filepath.Join(relSafe, filepath.Base(name))
+	if err := backendFor(root).Remove(target); err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
@@ -429,28 +382,28 @@ func handleRename(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "names required", 400)
 		return
 	}
-	_, abs, relSafe, err := resolveSafePath(root, rel)
+	root, _, relSafe, err := resolveSafePath(root, rel)
 	if err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	from := filepath.Join(abs, filepath.Base(oldName))
-	to := filepath.Join(abs, filepath.Base(newName))
-	if err := safeRename(from, to, abs); err != nil {
+	from := filepath.Join(relSafe, filepath.Base(oldName))
+	to := filepath.Join(relSafe, filepath.Base(newName))
+	if err := safeRename(backendFor(root), from, to, relSafe); err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
 	http.Redirect(w, r, fmt.Sprintf("/?root=%s&path=%s", urlq(root), urlq(relSafe)), http.StatusSeeOther)
 }
 
-func safeRename(from, to, dir string) error {
+func safeRename(backend Backend, from, to, dir string) error {
 	for _, p := range []string{from, to} {
 		rel, err := filepath.Rel(dir, p)
 		if err != nil || strings.HasPrefix(rel, "..") {
 			return errors.New("path escapes directory")
 		}
 	}
-	return os.Rename(from, to)
+	return backend.Rename(from, to)
 }
 
 var pageHTML = `<!doctype html>
@@ -461,6 +414,7 @@ var pageHTML = `<!doctype html>
   <title>Go File Manager</title>
   <script src="https://unpkg.com/htmx.org@2.0.3"></script>
   <script src="https://cdn.tailwindcss.com"></script>
+  <script src="https://unpkg.com/tus-js-client@4/dist/tus.min.js"></script>
 </head>
 <body class="bg-slate-50 text-slate-900">
   <div class="max-w-6xl mx-auto p-6">
@@ -477,9 +431,31 @@ var pageHTML = `<!doctype html>
           <input type="hidden" name="path" value="{{.CurrentPath}}" />
           <button class="bg-blue-600 text-white px-3 py-1 rounded">Abrir</button>
         </form>
+        <form method="get" action="/search" class="flex items-center gap-2">
+          <input type="hidden" name="root" value="{{.CurrentRoot}}" />
+          <input type="hidden" name="path" value="{{.CurrentPath}}" />
+          <input class="border rounded px-2 py-1" type="text" name="q" placeholder="Buscar arquivos..." />
+          <select name="kind" class="border rounded px-2 py-1">
+            <option value="">Qualquer tipo</option>
+            <option value="dir">Pasta</option>
+            <option value="file">Arquivo</option>
+            <option value="image">Imagem</option>
+            <option value="video">Vídeo</option>
+            <option value="archive">Arquivo compactado</option>
+          </select>
+          <button class="bg-slate-700 text-white px-3 py-1 rounded">Buscar</button>
+        </form>
+        {{if .User}}
+        <span class="text-sm text-slate-600">{{.User.Username}}</span>
+        <a class="text-sm text-blue-700 hover:underline" href="/logout">Sair</a>
+        {{end}}
       </nav>
     </header>
 
+    {{if .Flash}}
+    <div class="mb-4 text-sm text-slate-600">{{.Flash}}</div>
+    {{end}}
+
     <div class="text-sm breadcrumbs flex items-center gap-1 mb-4">
       {{range $i, $c := .Breadcrumb}}
         {{if $i}}<span>/</span>{{end}}
@@ -488,24 +464,61 @@ var pageHTML = `<!doctype html>
     </div>
 
     <section class="mb-4 p-4 bg-white rounded-2xl shadow">
+      {{if can .User "upload" .CurrentRoot .CurrentPath}}
       <form class="flex flex-wrap items-center gap-3" action="/upload" method="post" enctype="multipart/form-data">
         <input type="hidden" name="root" value="{{.CurrentRoot}}" />
         <input type="hidden" name="path" value="{{.CurrentPath}}" />
         <input class="border rounded px-3 py-2" type="file" name="files[]" multiple />
         <button class="bg-emerald-600 text-white px-4 py-2 rounded">Upload</button>
       </form>
+      {{end}}
+      {{if can .User "mkdir" .CurrentRoot .CurrentPath}}
       <form class="mt-3 flex items-center gap-2" action="/mkdir" method="post">
         <input type="hidden" name="root" value="{{.CurrentRoot}}" />
         <input type="hidden" name="path" value="{{.CurrentPath}}" />
         <input class="border rounded px-3 py-2" type="text" name="name" placeholder="Nova pasta" />
         <button class="bg-slate-700 text-white px-4 py-2 rounded">Criar pasta</button>
       </form>
+      {{end}}
+      {{if can .User "upload" .CurrentRoot .CurrentPath}}
+      <div class="mt-3 flex items-center gap-2">
+        <input id="tusFile" class="border rounded px-3 py-2" type="file" />
+        <button id="tusStart" type="button" class="bg-emerald-700 text-white px-4 py-2 rounded">
+          Upload resumível (arquivos grandes)
+        </button>
+        <span id="tusProgress" class="text-sm text-slate-600"></span>
+      </div>
+      <script>
+        document.getElementById('tusStart').addEventListener('click', function () {
+          var file = document.getElementById('tusFile').files[0];
+          if (!file) return;
+          var progress = document.getElementById('tusProgress');
+          var upload = new tus.Upload(file, {
+            endpoint: '/files/',
+            metadata: { filename: file.name, filetype: file.type },
+            headers: {},
+            onError: function (err) { progress.textContent = 'Erro: ' + err; },
+            onProgress: function (bytesSent, bytesTotal) {
+              progress.textContent = Math.round((bytesSent / bytesTotal) * 100) + '%';
+            },
+            onSuccess: function () {
+              progress.textContent = 'Concluído';
+              window.location.reload();
+            },
+          });
+          upload.options.endpoint = '/files/?root=' + encodeURIComponent('{{.CurrentRoot}}') + '&path=' + encodeURIComponent('{{.CurrentPath}}');
+          upload.start();
+        });
+      </script>
+      {{end}}
     </section>
 
     <section class="bg-white rounded-2xl shadow">
+      <form id="multiZipForm" action="/zip" method="post">
       <table class="w-full text-left">
         <thead>
           <tr class="border-b">
+            <th class="py-3 px-4 w-8"></th>
             <th class="py-3 px-4">Nome</th>
             <th class="py-3 px-4">Tamanho</th>
             <th class="py-3 px-4">Modificado</th>
@@ -514,13 +527,21 @@ var pageHTML = `<!doctype html>
         </thead>
         <tbody>
           {{if not .Entries}}
-            <tr><td class="py-6 px-4 text-slate-500" colspan="4">Vazio</td></tr>
+            <tr><td class="py-6 px-4 text-slate-500" colspan="5">Vazio</td></tr>
           {{end}}
           {{range .Entries}}
             <tr class="border-b hover:bg-slate-50">
+              <td class="py-2 px-4">
+                <input type="checkbox" name="files" value="{{.RelPath}}" form="multiZipForm" />
+              </td>
               <td class="py-2 px-4">
                 {{if .IsDir}}
                   <a class="text-blue-700 hover:underline" href="/?root={{$.CurrentRoot}}&path={{.RelPath}}">📁 {{.Name}}</a>
+                {{else if eq .PreviewKind "image"}}
+                  <a class="flex items-center gap-2 text-slate-800 hover:underline" href="/download?root={{$.CurrentRoot}}&path={{.RelPath}}">
+                    <img class="w-8 h-8 object-cover rounded" loading="lazy" src="/preview?root={{$.CurrentRoot}}&path={{.RelPath}}&size=64" alt="" />
+                    {{.Name}}
+                  </a>
                 {{else}}
                   <a class="text-slate-800 hover:underline" href="/download?root={{$.CurrentRoot}}&path={{.RelPath}}">📄 {{.Name}}</a>
                 {{end}}
@@ -529,14 +550,27 @@ var pageHTML = `<!doctype html>
               <td class="py-2 px-4">{{fmtTime .ModTime}}</td>
               <td class="py-2 px-4">
                 <div class="flex items-center gap-2 justify-end">
-                  {{if not .IsDir}}
+                  {{if or (eq .PreviewKind "text") (eq .PreviewKind "archive")}}
+                  <button type="button" class="px-2 py-1 rounded border"
+                    hx-get="/preview?root={{$.CurrentRoot}}&path={{.RelPath}}"
+                    hx-target="#previewModalBody" hx-swap="innerHTML"
+                    onclick="document.getElementById('previewModal').showModal()">Visualizar</button>
+                  {{end}}
+                  {{if eq .PreviewKind "pdf"}}
+                  <button type="button" class="px-2 py-1 rounded border"
+                    onclick="document.getElementById('previewModalBody').innerHTML = '<img class=&quot;max-w-full&quot; src=&quot;/preview?root={{$.CurrentRoot}}&path={{.RelPath}}&quot;>'; document.getElementById('previewModal').showModal()">Visualizar</button>
+                  {{end}}
+                  {{if and (not .IsDir) (can $.User "download" $.CurrentRoot .RelPath)}}
                   <a class="px-2 py-1 rounded border" href="/download?root={{$.CurrentRoot}}&path={{.RelPath}}">Baixar</a>
                   {{end}}
+                  {{if can $.User "zip" $.CurrentRoot .RelPath}}
                   <form action="/zip" method="post">
                     <input type="hidden" name="root" value="{{$.CurrentRoot}}" />
                     <input type="hidden" name="path" value="{{.RelPath}}" />
                     <button class="px-2 py-1 rounded border">Zip</button>
                   </form>
+                  {{end}}
+                  {{if can $.User "rename" $.CurrentRoot $.CurrentPath}}
                   <form action="/rename" method="post" class="flex items-center gap-1">
                     <input type="hidden" name="root" value="{{$.CurrentRoot}}" />
                     <input type="hidden" name="path" value="{{$.CurrentPath}}" />
@@ -544,19 +578,43 @@ var pageHTML = `<!doctype html>
                     <input class="border rounded px-2 py-1 text-sm" type="text" name="new" placeholder="Novo nome" />
                     <button class="px-2 py-1 rounded border">Renomear</button>
                   </form>
+                  {{end}}
+                  {{if can $.User "delete" $.CurrentRoot $.CurrentPath}}
                   <form action="/delete" method="post" onsubmit="return confirm('Excluir {{.Name}}? Esta ação é permanente.');">
                     <input type="hidden" name="root" value="{{$.CurrentRoot}}" />
                     <input type="hidden" name="path" value="{{$.CurrentPath}}" />
                     <input type="hidden" name="name" value="{{.Name}}" />
                     <button class="px-2 py-1 rounded border text-red-700">Excluir</button>
                   </form>
+                  {{end}}
                 </div>
               </td>
             </tr>
           {{end}}
         </tbody>
       </table>
+      {{if can .User "zip" .CurrentRoot .CurrentPath}}
+      <div class="flex items-center gap-2 p-4 border-t">
+        <input type="hidden" name="root" value="{{.CurrentRoot}}" />
+        <select name="format" class="border rounded px-2 py-1">
+          <option value="zip">.zip</option>
+          <option value="tar">.tar</option>
+          <option value="tar.gz">.tar.gz</option>
+          <option value="tar.zst">.tar.zst</option>
+        </select>
+        <button class="px-3 py-1 rounded border">Baixar selecionados</button>
+      </div>
+      {{end}}
+      </form>
     </section>
+
+    <dialog id="previewModal" class="rounded-xl shadow-lg p-0 w-full max-w-3xl">
+      <div class="flex items-center justify-between p-3 border-b">
+        <span class="font-semibold">Visualização</span>
+        <button type="button" class="px-2 py-1 rounded border" onclick="document.getElementById('previewModal').close()">Fechar</button>
+      </div>
+      <div id="previewModalBody" class="p-4 max-h-[70vh] overflow-auto"></div>
+    </dialog>
   </div>
 </body>
 </html>`
@@ -566,23 +624,44 @@ func main() {
 	if len(cfg.AllowedRoots) == 0 {
 		log.Fatal("No ALLOWED_ROOTS configured")
 	}
+	var err error
+	userStore, err = loadUserStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	globalIndexer = newIndexer(cfg.AllowedRoots, indexIntervalFromEnv())
+	globalIndexer.Start()
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", basicAuth(handleBrowse))
-	mux.HandleFunc("/download", basicAuth(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/login", handleLogin)
+	mux.HandleFunc("/logout", handleLogout)
+	mux.HandleFunc("/", basicAuth(requirePermission(verbList, queryLocator)(handleBrowse)))
+	mux.HandleFunc("/download", basicAuth(requirePermission(verbDownload, queryLocator)(func(w http.ResponseWriter, r *http.Request) {
 		root := r.URL.Query().Get("root")
 		rel := r.URL.Query().Get("path")
-		_, abs, _, err := resolveSafePath(root, rel)
+		root, _, relSafe, err := resolveSafePath(root, rel)
 		if err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		http.ServeFile(w, r, abs)
-	}))
-	mux.HandleFunc("/zip", basicAuth(handleZip))
-	mux.HandleFunc("/upload", basicAuth(handleUpload))
-	mux.HandleFunc("/mkdir", basicAuth(handleMkdir))
-	mux.HandleFunc("/delete", basicAuth(handleDelete))
-	mux.HandleFunc("/rename", basicAuth(handleRename))
+		backend := backendFor(root)
+		st, err := backend.Stat(relSafe)
+		if err != nil {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filepath.Base(relSafe)))
+		serveBackendFile(w, r, backend, relSafe, st, "no-cache")
+	})))
+	mux.HandleFunc("/search", basicAuth(requirePermission(verbList, queryLocator)(handleSearch)))
+	mux.HandleFunc("/preview", basicAuth(requirePermission(verbDownload, queryLocator)(handlePreview)))
+	mux.HandleFunc("/zip", basicAuth(requirePermission(verbZip, formLocator)(handleZip)))
+	mux.HandleFunc("/upload", basicAuth(requirePermission(verbUpload, formLocator)(handleUpload)))
+	mux.HandleFunc("/files/", basicAuth(handleTus))
+	mux.HandleFunc("/mkdir", basicAuth(requirePermission(verbMkdir, formLocator)(handleMkdir)))
+	mux.HandleFunc("/delete", basicAuth(requirePermission(verbDelete, formLocator)(handleDelete)))
+	mux.HandleFunc("/rename", basicAuth(requirePermission(verbRename, formLocator)(handleRename)))
+	mux.Handle("/static/", http.StripPrefix("/static/", staticHandler()))
 
 	addr := ":8080"
 	log.Printf("Go File Manager listening on %s (roots: %v)\n", addr, cfg.AllowedRoots)
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+func withAllowedRoots(t *testing.T, roots []string, fn func()) {
+	t.Helper()
+	prev := cfg
+	cfg = Config{AllowedRoots: roots}
+	defer func() { cfg = prev }()
+	fn()
+}
+
+// resolveSafePath clamps ".." segments against the root before joining,
+// so a traversal attempt resolves to a path still inside the root rather
+// than erroring.
+func TestResolveSafePathClampsTraversal(t *testing.T) {
+	withAllowedRoots(t, []string{"/data/sdd1"}, func() {
+		_, abs, _, err := resolveSafePath("/data/sdd1", "../../etc/passwd")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if abs != "/data/sdd1/etc/passwd" {
+			t.Errorf("abs = %q, want it clamped inside the root", abs)
+		}
+	})
+}
+
+func TestResolveSafePathRejectsUnknownRoot(t *testing.T) {
+	withAllowedRoots(t, []string{"/data/sdd1"}, func() {
+		if _, _, _, err := resolveSafePath("/data/hdd1", "foo"); err == nil {
+			t.Fatal("expected an error for a root not in AllowedRoots, got nil")
+		}
+	})
+}
+
+func TestResolveSafePathAllowsNestedPath(t *testing.T) {
+	withAllowedRoots(t, []string{"/data/sdd1"}, func() {
+		root, abs, rel, err := resolveSafePath("/data/sdd1", "photos/2024/beach.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if root != "/data/sdd1" {
+			t.Errorf("root = %q, want /data/sdd1", root)
+		}
+		if abs != "/data/sdd1/photos/2024/beach.jpg" {
+			t.Errorf("abs = %q", abs)
+		}
+		if rel != "photos/2024/beach.jpg" {
+			t.Errorf("rel = %q", rel)
+		}
+	})
+}
+
+// S3/SFTP roots use "scheme://" URIs; filepath.Clean would mangle the
+// double slash, so resolveSafePath must leave them alone.
+func TestResolveSafePathPreservesURIRoot(t *testing.T) {
+	withAllowedRoots(t, []string{"s3://bucket/prefix"}, func() {
+		root, _, rel, err := resolveSafePath("s3://bucket/prefix", "dir/file.txt")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if root != "s3://bucket/prefix" {
+			t.Errorf("root = %q, want s3://bucket/prefix", root)
+		}
+		if rel != "dir/file.txt" {
+			t.Errorf("rel = %q", rel)
+		}
+	})
+}
+
+func TestResolveSafePathClampsTraversalOnURIRoot(t *testing.T) {
+	withAllowedRoots(t, []string{"s3://bucket/prefix"}, func() {
+		_, _, rel, err := resolveSafePath("s3://bucket/prefix", "../../other-prefix/secret")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rel != "other-prefix/secret" {
+			t.Errorf("rel = %q, want it clamped inside the root", rel)
+		}
+	})
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/bodgit/sevenzip"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gen2brain/go-fitz"
+	"golang.org/x/image/draw"
+)
+
+// previewKind classifies a file by extension for the purposes of the
+// /preview endpoint and the listing UI: which entries get an inline
+// thumbnail, which get a "Preview" button, and which get neither.
+func previewKind(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp":
+		return "image"
+	case ".pdf":
+		return "pdf"
+	case ".zip", ".tar", ".gz", ".tgz", ".7z":
+		return "archive"
+	case ".go", ".js", ".ts", ".py", ".java", ".c", ".h", ".cpp", ".rb", ".rs",
+		".sh", ".yaml", ".yml", ".json", ".md", ".txt", ".html", ".css", ".sql":
+		return "text"
+	default:
+		return ""
+	}
+}
+
+// previewCacheDir holds generated thumbnails, keyed by path+mtime+size so a
+// re-request after the source changes regenerates instead of serving a
+// stale image. The directory is capped at previewCacheMaxBytes, evicting
+// the least-recently-read entries first (see evictLRU), so it doesn't grow
+// without bound as users browse large trees.
+func previewCacheDir() string {
+	return getenv("PREVIEW_CACHE_DIR", filepath.Join(os.TempDir(), "gofilemanager-previews"))
+}
+
+// previewCacheMaxBytes caps the on-disk thumbnail cache.
+func previewCacheMaxBytes() int64 {
+	n, err := strconv.ParseInt(getenv("PREVIEW_CACHE_MAX_BYTES", ""), 10, 64)
+	if err != nil || n <= 0 {
+		return 512 * 1024 * 1024 // 512MB default
+	}
+	return n
+}
+
+func thumbnailCacheKey(root, relPath string, info os.FileInfo, variant string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%s", root, relPath, info.Size(), info.ModTime().UnixNano(), variant)))
+	return hex.EncodeToString(sum[:])
+}
+
+func readCachedThumbnail(key string) ([]byte, bool) {
+	path := filepath.Join(previewCacheDir(), key+".webp")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now) // bump recency so eviction treats this as just-used
+	return b, true
+}
+
+func writeCachedThumbnail(key string, data []byte) error {
+	dir := previewCacheDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, key+".webp"), data, 0o644); err != nil {
+		return err
+	}
+	evictLRU(dir, previewCacheMaxBytes())
+	return nil
+}
+
+// evictLRU removes the least-recently-read files in dir (oldest mtime
+// first) until its total size is back under maxBytes. readCachedThumbnail
+// bumps a file's mtime on every hit, so this behaves as an LRU policy even
+// though the eviction itself only runs on writes.
+func evictLRU(dir string, maxBytes int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	files := make([]cacheFile, 0, len(entries))
+	var total int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{path: filepath.Join(dir, e.Name()), size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+	if total <= maxBytes {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+}
+
+// encodeThumbnail resizes img so its longest side is at most maxSide and
+// encodes it as WebP.
+func encodeThumbnail(img image.Image, maxSide int) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > h && w > maxSide {
+		h = h * maxSide / w
+		w = maxSide
+	} else if h >= w && h > maxSide {
+		w = w * maxSide / h
+		h = maxSide
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := nativewebp.Encode(&buf, dst, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func thumbnailSizeParam(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("size"))
+	if err != nil || n <= 0 {
+		return 200
+	}
+	if n > 1024 {
+		n = 1024
+	}
+	return n
+}
+
+// handlePreview answers /preview?root=&path=&size= for a single file,
+// dispatching by file extension (previewKind): images and PDFs render a
+// cached WebP thumbnail, text/code files render a syntax-highlighted HTML
+// fragment, and archives render an HTML tree of their contents without
+// extracting them. The handler is not picked by content MIME type; MIME
+// detection only rejects text-extension files that look binary. Anything
+// else reports 415.
+func handlePreview(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	root, _, relSafe, err := resolveSafePath(q.Get("root"), q.Get("path"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	backend := backendFor(root)
+	st, err := backend.Stat(relSafe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if st.IsDir() {
+		http.Error(w, "cannot preview a directory", http.StatusBadRequest)
+		return
+	}
+
+	switch previewKind(relSafe) {
+	case "image":
+		servePreviewImage(w, r, backend, root, relSafe, st)
+	case "pdf":
+		servePreviewPDF(w, r, backend, root, relSafe, st)
+	case "text":
+		servePreviewText(w, backend, relSafe)
+	case "archive":
+		servePreviewArchive(w, backend, relSafe)
+	default:
+		http.Error(w, "no preview available for this file type", http.StatusUnsupportedMediaType)
+	}
+}
+
+func servePreviewImage(w http.ResponseWriter, r *http.Request, backend Backend, root, relSafe string, st os.FileInfo) {
+	size := thumbnailSizeParam(r)
+	key := thumbnailCacheKey(root, relSafe, st, fmt.Sprintf("img-%d", size))
+	if data, ok := readCachedThumbnail(key); ok {
+		writeThumbnail(w, data)
+		return
+	}
+
+	f, err := backend.Open(relSafe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		http.Error(w, "unreadable image: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	data, err := encodeThumbnail(img, size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_ = writeCachedThumbnail(key, data) // best effort; a cache miss just regenerates next time
+	writeThumbnail(w, data)
+}
+
+// servePreviewPDF rasterizes the first page of a PDF into the same
+// thumbnail cache used for images. go-fitz wraps MuPDF via cgo, the one
+// cgo dependency in this codebase; there's no pure-Go PDF rasterizer, and
+// rendering a preview image is cheaper than shipping a second process.
+func servePreviewPDF(w http.ResponseWriter, r *http.Request, backend Backend, root, relSafe string, st os.FileInfo) {
+	size := thumbnailSizeParam(r)
+	key := thumbnailCacheKey(root, relSafe, st, fmt.Sprintf("pdf-%d", size))
+	if data, ok := readCachedThumbnail(key); ok {
+		writeThumbnail(w, data)
+		return
+	}
+
+	f, err := backend.Open(relSafe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	raw, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc, err := fitz.NewFromMemory(raw)
+	if err != nil {
+		http.Error(w, "unreadable pdf: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	defer doc.Close()
+	img, err := doc.Image(0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	data, err := encodeThumbnail(img, size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_ = writeCachedThumbnail(key, data)
+	writeThumbnail(w, data)
+}
+
+func writeThumbnail(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "image/webp")
+	w.Header().Set("Cache-Control", "public, max-age=604800, immutable")
+	w.Write(data)
+}
+
+// previewTextLimit caps how much of a text file gets highlighted; beyond
+// this it's almost certainly not something worth rendering inline anyway.
+const previewTextLimit = 512 * 1024
+
+func servePreviewText(w http.ResponseWriter, backend Backend, relSafe string) {
+	f, err := backend.Open(relSafe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	raw, err := io.ReadAll(io.LimitReader(f, previewTextLimit+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	truncated := len(raw) > previewTextLimit
+	if truncated {
+		raw = raw[:previewTextLimit]
+	}
+
+	if mime := mimetype.Detect(raw); mime.Is("application/octet-stream") {
+		http.Error(w, "file does not look like text", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	lexer := lexers.Match(relSafe)
+	if lexer == nil {
+		lexer = lexers.Analyse(string(raw))
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, string(raw))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	formatter := chromahtml.New(chromahtml.WithClasses(false), chromahtml.TabWidth(4))
+	style := styles.Get("github")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := formatter.Format(w, style, iterator); err != nil {
+		log.Println("preview: chroma format error:", err)
+		return
+	}
+	if truncated {
+		fmt.Fprintf(w, "<p><em>(truncated at %d bytes)</em></p>", previewTextLimit)
+	}
+}
+
+// archiveNode is one entry in the tree rendered by servePreviewArchive.
+type archiveNode struct {
+	Name     string
+	Size     int64
+	IsDir    bool
+	Children []*archiveNode
+}
+
+func insertArchiveEntry(root *archiveNode, path string, size int64, isDir bool) {
+	parts := strings.Split(strings.Trim(filepath.ToSlash(path), "/"), "/")
+	cur := root
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		last := i == len(parts)-1
+		var child *archiveNode
+		for _, c := range cur.Children {
+			if c.Name == part {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &archiveNode{Name: part, IsDir: !last || isDir}
+			cur.Children = append(cur.Children, child)
+		}
+		if last {
+			child.Size = size
+			child.IsDir = isDir
+		}
+		cur = child
+	}
+}
+
+func renderArchiveTree(w io.Writer, node *archiveNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		return a.Name < b.Name
+	})
+	fmt.Fprint(w, "<ul class=\"pl-4\">")
+	for _, c := range node.Children {
+		if c.IsDir {
+			fmt.Fprintf(w, "<li>📁 %s", html.EscapeString(c.Name))
+			renderArchiveTree(w, c)
+			fmt.Fprint(w, "</li>")
+		} else {
+			fmt.Fprintf(w, "<li>📄 %s <span class=\"text-slate-500\">(%s)</span></li>", html.EscapeString(c.Name), humanSize(c.Size))
+		}
+	}
+	fmt.Fprint(w, "</ul>")
+}
+
+// servePreviewArchive lists the contents of a zip/tar(.gz)/7z file as an
+// HTML tree without extracting it anywhere on disk.
+func servePreviewArchive(w http.ResponseWriter, backend Backend, relSafe string) {
+	f, err := backend.Open(relSafe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	raw, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	root := &archiveNode{Name: ".", IsDir: true}
+	ext := strings.ToLower(filepath.Ext(relSafe))
+	switch {
+	case ext == ".zip":
+		zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			http.Error(w, "unreadable zip: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		for _, zf := range zr.File {
+			insertArchiveEntry(root, zf.Name, int64(zf.UncompressedSize64), zf.FileInfo().IsDir())
+		}
+	case ext == ".7z":
+		zr, err := sevenzip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			http.Error(w, "unreadable 7z: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		for _, zf := range zr.File {
+			insertArchiveEntry(root, zf.Name, int64(zf.UncompressedSize), zf.FileInfo().IsDir())
+		}
+	default: // .tar, .tar.gz/.tgz
+		var r io.Reader = bytes.NewReader(raw)
+		if ext == ".gz" || ext == ".tgz" {
+			gzr, err := gzip.NewReader(r)
+			if err != nil {
+				http.Error(w, "unreadable tar.gz: "+err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			defer gzr.Close()
+			r = gzr
+		}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				http.Error(w, "unreadable tar: "+err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			insertArchiveEntry(root, hdr.Name, hdr.Size, hdr.Typeflag == tar.TypeDir)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	renderArchiveTree(w, root)
+}
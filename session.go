@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const sessionCookieName = "gfm_session"
+const sessionTTL = 24 * time.Hour
+
+type sessionEntry struct {
+	Username string
+	Expires  time.Time
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = map[string]sessionEntry{}
+)
+
+func newSessionToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func createSession(w http.ResponseWriter, username string) {
+	token := newSessionToken()
+	sessionsMu.Lock()
+	sessions[token] = sessionEntry{Username: username, Expires: time.Now().Add(sessionTTL)}
+	sessionsMu.Unlock()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(sessionTTL),
+	})
+}
+
+func destroySession(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookieName); err == nil {
+		sessionsMu.Lock()
+		delete(sessions, c.Value)
+		sessionsMu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1})
+}
+
+// sessionUsername returns the username tied to a valid, unexpired session
+// cookie on the request, or "" if there isn't one.
+func sessionUsername(r *http.Request) string {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	sessionsMu.Lock()
+	entry, ok := sessions[c.Value]
+	sessionsMu.Unlock()
+	if !ok || time.Now().After(entry.Expires) {
+		return ""
+	}
+	return entry.Username
+}
+
+var loginPageHTML = `<!doctype html>
+<html lang="pt-br">
+<head>
+  <meta charset="utf-8" />
+  <title>Login - Go File Manager</title>
+  <script src="https://cdn.tailwindcss.com"></script>
+</head>
+<body class="bg-slate-50 text-slate-900">
+  <div class="max-w-sm mx-auto mt-24 p-6 bg-white rounded-2xl shadow">
+    <h1 class="text-xl font-bold mb-4">Entrar</h1>
+    {{if .Error}}<div class="text-red-700 text-sm mb-3">{{.Error}}</div>{{end}}
+    <form method="post" action="/login" class="flex flex-col gap-3">
+      <input class="border rounded px-3 py-2" type="text" name="username" placeholder="Usuário" />
+      <input class="border rounded px-3 py-2" type="password" name="password" placeholder="Senha" />
+      <button class="bg-blue-600 text-white px-4 py-2 rounded">Entrar</button>
+    </form>
+  </div>
+</body>
+</html>`
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		renderLogin(w, "")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	username := r.Form.Get("username")
+	password := r.Form.Get("password")
+	if _, ok := userStore.Authenticate(username, password); !ok {
+		renderLogin(w, "Usuário ou senha inválidos")
+		return
+	}
+	createSession(w, username)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func renderLogin(w http.ResponseWriter, errMsg string) {
+	tmpl := template.Must(template.New("login").Parse(loginPageHTML))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl.Execute(w, struct{ Error string }{errMsg})
+}
+
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	destroySession(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tusUploadMeta is the sidecar JSON stored alongside a staged upload,
+// recording everything completeTusUpload needs to know where the
+// finished file belongs once all bytes have arrived.
+type tusUploadMeta struct {
+	ID       string `json:"id"`
+	Root     string `json:"root"`
+	Dir      string `json:"dir"` // target directory, relative to root
+	Filename string `json:"filename"`
+	Length   int64  `json:"length"`
+	Offset   int64  `json:"offset"`
+}
+
+func tusStagingDir() string {
+	return getenv("TUS_STAGING_DIR", filepath.Join(os.TempDir(), "go-file-manager-tus"))
+}
+
+func tusMetaPath(id string) string { return filepath.Join(tusStagingDir(), id+".tus") }
+func tusDataPath(id string) string { return filepath.Join(tusStagingDir(), id+".bin") }
+
+func newTusID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func loadTusMeta(id string) (*tusUploadMeta, error) {
+	b, err := os.ReadFile(tusMetaPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var m tusUploadMeta
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func saveTusMeta(m *tusUploadMeta) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tusMetaPath(m.ID), b, 0o644)
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header: a
+// comma-separated list of "key base64value" pairs.
+func parseUploadMetadata(header string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		key := parts[0]
+		val := ""
+		if len(parts) == 2 {
+			if b, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+				val = string(b)
+			}
+		}
+		out[key] = val
+	}
+	return out
+}
+
+func tusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", "1.0.0")
+}
+
+// handleTusCreate implements tus POST: it creates a staging file and
+// sidecar metadata, returning the upload's Location.
+func handleTusCreate(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	root := r.URL.Query().Get("root")
+	rel := r.URL.Query().Get("path")
+	root, _, relSafe, err := resolveSafePath(root, rel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if user := userFromContext(r); user == nil || !user.Can(verbUpload, root, relSafe) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	meta := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	filename := filepath.Base(meta["filename"])
+	if filename == "" || filename == "." {
+		http.Error(w, "Upload-Metadata must include filename", http.StatusBadRequest)
+		return
+	}
+
+	if err := os.MkdirAll(tusStagingDir(), 0o755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := newTusID()
+	f, err := os.Create(tusDataPath(id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+
+	m := &tusUploadMeta{ID: id, Root: root, Dir: relSafe, Filename: filename, Length: length}
+	if err := saveTusMeta(m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/files/%s", id))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleTusHead reports how many bytes of an in-progress upload have
+// landed so the client can resume after a dropped connection.
+func handleTusHead(w http.ResponseWriter, r *http.Request, id string) {
+	tusHeaders(w)
+	m, err := loadTusMeta(id)
+	if err != nil {
+		http.Error(w, "unknown upload", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(m.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(m.Length, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleTusPatch appends the request body at the given Upload-Offset,
+// fsyncing after every chunk so a crash mid-transfer doesn't lose bytes
+// the client believes were already acknowledged.
+func handleTusPatch(w http.ResponseWriter, r *http.Request, id string) {
+	tusHeaders(w)
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	m, err := loadTusMeta(id)
+	if err != nil {
+		http.Error(w, "unknown upload", http.StatusNotFound)
+		return
+	}
+	if user := userFromContext(r); user == nil || !user.Can(verbUpload, m.Root, m.Dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != m.Offset {
+		http.Error(w, "offset mismatch", http.StatusConflict)
+		return
+	}
+
+	f, err := os.OpenFile(tusDataPath(id), os.O_WRONLY, 0o644)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, 0); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n, err := f.ReadFrom(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := f.Sync(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m.Offset += n
+	if err := saveTusMeta(m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(m.Offset, 10))
+
+	if m.Offset >= m.Length {
+		if err := completeTusUpload(m); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTusDelete aborts an in-progress upload and discards its staged
+// bytes and metadata.
+func handleTusDelete(w http.ResponseWriter, r *http.Request, id string) {
+	tusHeaders(w)
+	m, err := loadTusMeta(id)
+	if err != nil {
+		http.Error(w, "unknown upload", http.StatusNotFound)
+		return
+	}
+	if user := userFromContext(r); user == nil || !user.Can(verbUpload, m.Root, m.Dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	os.Remove(tusDataPath(id))
+	os.Remove(tusMetaPath(id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completeTusUpload moves a fully-received staged file into its target
+// directory, resolved the same way every other write path resolves one.
+func completeTusUpload(m *tusUploadMeta) error {
+	backend := backendFor(m.Root)
+	if backend == nil {
+		return fmt.Errorf("unknown root %q", m.Root)
+	}
+	staged, err := os.Open(tusDataPath(m.ID))
+	if err != nil {
+		return err
+	}
+	defer staged.Close()
+
+	dstPath := filepath.Join(m.Dir, m.Filename)
+	dst, err := backend.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, staged); err != nil {
+		return err
+	}
+	os.Remove(tusDataPath(m.ID))
+	os.Remove(tusMetaPath(m.ID))
+	return nil
+}
+
+// handleTus dispatches the tus 1.0.0 methods under /files/ and /files/{id}.
+func handleTus(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/files/")
+	switch r.Method {
+	case http.MethodPost:
+		handleTusCreate(w, r)
+	case http.MethodHead:
+		handleTusHead(w, r, id)
+	case http.MethodPatch:
+		handleTusPatch(w, r, id)
+	case http.MethodDelete:
+		handleTusDelete(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
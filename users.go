@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// verbs recognized by the permission system. Buttons in pageHTML are
+// hidden client-side via the "can" template func (canDo), and the
+// authorization middleware enforces the same check server-side.
+const (
+	verbList      = "list"
+	verbDownload  = "download"
+	verbUpload    = "upload"
+	verbOverwrite = "overwrite"
+	verbMkdir     = "mkdir"
+	verbRename    = "rename"
+	verbDelete    = "delete"
+	verbZip       = "zip"
+)
+
+// User is one account in the store. Permissions maps a path glob (matched
+// against "root/relpath") to the verbs allowed under it; the most
+// specific matching glob wins. WebClient disables every web UI write
+// action regardless of Permissions, for accounts meant to be
+// read-only/API-only.
+type User struct {
+	Username     string              `yaml:"username"`
+	PasswordHash string              `yaml:"password_hash"`
+	Permissions  map[string][]string `yaml:"permissions"`
+	WebClient    bool                `yaml:"web_client"`
+}
+
+type UserStore struct {
+	Users map[string]*User
+}
+
+type usersFile struct {
+	Users []*User `yaml:"users"`
+}
+
+// loadUserStore reads USERS_FILE (YAML). When unset it falls back to a
+// single admin account built from the legacy USERNAME/PASSWORD env vars
+// with unrestricted permissions, so existing deployments keep working.
+func loadUserStore() (*UserStore, error) {
+	path := getenv("USERS_FILE", "")
+	if path == "" {
+		return legacyUserStore(), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading USERS_FILE: %w", err)
+	}
+	var parsed usersFile
+	if err := yaml.Unmarshal(b, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing USERS_FILE: %w", err)
+	}
+	store := &UserStore{Users: map[string]*User{}}
+	for _, u := range parsed.Users {
+		store.Users[u.Username] = u
+	}
+	return store, nil
+}
+
+func legacyUserStore() *UserStore {
+	username := readSecretOrEnv("USERNAME_FILE", "USERNAME", "admin")
+	password := readSecretOrEnv("PASSWORD_FILE", "PASSWORD", "changeme")
+	hash, err := HashPassword(password)
+	if err != nil {
+		panic(err) // bcrypt only fails on malformed cost/length, never here
+	}
+	return &UserStore{Users: map[string]*User{
+		username: {
+			Username:     username,
+			PasswordHash: hash,
+			Permissions:  map[string][]string{"*": {verbList, verbDownload, verbUpload, verbOverwrite, verbMkdir, verbRename, verbDelete, verbZip}},
+		},
+	}}
+}
+
+// HashPassword hashes a plaintext password for storage in USERS_FILE.
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(b), err
+}
+
+func (s *UserStore) Authenticate(username, password string) (*User, bool) {
+	u, ok := s.Users[username]
+	if !ok {
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+// Can reports whether the user may perform verb on root+relPath. The
+// glob with the longest matching prefix wins so a narrower rule (e.g.
+// "/data/sdd1/public/*") overrides a broader one (e.g. "/data/sdd1/*").
+func (u *User) Can(verb, root, relPath string) bool {
+	if u.WebClient && verb != verbList && verb != verbDownload {
+		return false
+	}
+	target := joinRootRel(root, relPath)
+	bestLen := -1
+	var bestVerbs []string
+	for glob, verbs := range u.Permissions {
+		if !globMatchesPath(glob, target) {
+			continue
+		}
+		if l := len(strings.TrimSuffix(glob, "*")); l > bestLen {
+			bestLen = l
+			bestVerbs = verbs
+		}
+	}
+	for _, v := range bestVerbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// joinRootRel builds the "/"-joined root+relPath string permission globs
+// are matched against. filepath.Join/Clean would collapse the "://" in an
+// S3/SFTP root (e.g. "s3://bucket/prefix" -> "s3:/bucket/prefix"), so URI
+// roots are joined with a plain string concatenation instead.
+func joinRootRel(root, relPath string) string {
+	relPath = filepath.ToSlash(relPath)
+	if relPath == "." || relPath == "" {
+		return root
+	}
+	if strings.Contains(root, "://") {
+		return strings.TrimSuffix(root, "/") + "/" + relPath
+	}
+	return filepath.ToSlash(filepath.Join(root, relPath))
+}
+
+// globMatchesPath matches a permission glob against a full "/"-joined
+// path. Unlike filepath.Match, a trailing "*" here also matches across
+// path separators (so "/data/sdd1/*" covers every file under that root,
+// not just direct children), since permission rules are meant to scope
+// whole subtrees rather than single directory levels. A subtree glob also
+// matches its own root directory (target == prefix with the trailing "/"
+// stripped), since joinRootRel returns the bare root when relPath is "."
+// and a user scoped to "/data/sdd1/*" should still be able to browse
+// "/data/sdd1" itself.
+func globMatchesPath(glob, target string) bool {
+	if glob == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(glob, "*"); ok {
+		return strings.HasPrefix(target, prefix) || target == strings.TrimSuffix(prefix, "/")
+	}
+	return glob == target
+}
@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestGlobMatchesPath(t *testing.T) {
+	cases := []struct {
+		glob, target string
+		want         bool
+	}{
+		{"*", "/data/sdd1/anything/deep/path", true},
+		{"/data/sdd1/*", "/data/sdd1/foo/bar", true},
+		{"/data/sdd1/*", "/data/sdd1", true},
+		{"/data/sdd1/*", "/data/hdd1/foo", false},
+		{"/data/sdd1/public", "/data/sdd1/public", true},
+		{"/data/sdd1/public", "/data/sdd1/public/x", false},
+	}
+	for _, c := range cases {
+		if got := globMatchesPath(c.glob, c.target); got != c.want {
+			t.Errorf("globMatchesPath(%q, %q) = %v, want %v", c.glob, c.target, got, c.want)
+		}
+	}
+}
+
+func TestUserCanDefaultLegacyPermissions(t *testing.T) {
+	u := &User{Permissions: map[string][]string{"*": {verbList, verbDownload}}}
+	if !u.Can(verbList, "/data/sdd1", "some/deep/path.txt") {
+		t.Error("wildcard permission should match a deep path")
+	}
+	if u.Can(verbDelete, "/data/sdd1", "some/deep/path.txt") {
+		t.Error("verb not granted by any rule should be denied")
+	}
+}
+
+func TestUserCanMostSpecificGlobWins(t *testing.T) {
+	u := &User{Permissions: map[string][]string{
+		"/data/sdd1/*":         {verbList, verbDownload},
+		"/data/sdd1/private/*": {},
+	}}
+	if u.Can(verbDownload, "/data/sdd1", "private/secret.txt") {
+		t.Error("the narrower /private/* rule should override the broader one and deny download")
+	}
+	if !u.Can(verbDownload, "/data/sdd1", "public/readme.txt") {
+		t.Error("the broader rule should still apply outside /private/*")
+	}
+}
+
+// A user scoped with a trailing-"*" glob (rather than the catch-all "*")
+// should still be able to browse the root of their own allowed root.
+func TestUserCanMatchesOwnRoot(t *testing.T) {
+	u := &User{Permissions: map[string][]string{"/data/sdd1/*": {verbList}}}
+	if !u.Can(verbList, "/data/sdd1", "") {
+		t.Error("a subtree glob should also grant access to its own root")
+	}
+}
+
+// Permission globs on S3/SFTP roots are written against the root string as
+// configured in ALLOWED_ROOTS (e.g. "s3://bucket/prefix/*"); joinRootRel
+// must not mangle the "://" the way filepath.Join/Clean would.
+func TestUserCanMatchesURIRoot(t *testing.T) {
+	u := &User{Permissions: map[string][]string{"s3://bucket/prefix/*": {verbDownload}}}
+	if !u.Can(verbDownload, "s3://bucket/prefix", "photos/beach.jpg") {
+		t.Error("glob scoped to an S3 root should match a file under that root")
+	}
+	if u.Can(verbDownload, "s3://otherbucket/prefix", "photos/beach.jpg") {
+		t.Error("glob scoped to one bucket must not match a different bucket")
+	}
+}
+
+func TestJoinRootRel(t *testing.T) {
+	cases := []struct {
+		root, rel, want string
+	}{
+		{"/data/sdd1", "foo/bar.txt", "/data/sdd1/foo/bar.txt"},
+		{"/data/sdd1", ".", "/data/sdd1"},
+		{"s3://bucket/prefix", "foo/bar.txt", "s3://bucket/prefix/foo/bar.txt"},
+		{"s3://bucket/prefix", ".", "s3://bucket/prefix"},
+	}
+	for _, c := range cases {
+		if got := joinRootRel(c.root, c.rel); got != c.want {
+			t.Errorf("joinRootRel(%q, %q) = %q, want %q", c.root, c.rel, got, c.want)
+		}
+	}
+}